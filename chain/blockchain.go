@@ -1,19 +1,17 @@
 package chain
 
 import (
-	"github.com/boltdb/bolt"
 	"fmt"
 	"crypto/ecdsa"
 	"log"
 	"errors"
-	"os"
-	"github.com/michain/dotcoin/util"
+	"sync"
 	"github.com/michain/dotcoin/storage"
 	"github.com/michain/dotcoin/wallet"
 	"github.com/michain/dotcoin/util/hashx"
-	"sync"
 	"encoding/hex"
 	"github.com/michain/dotcoin/logx"
+	"github.com/michain/dotcoin/chain/validation"
 )
 
 const genesisCoinbaseData = "The Times 15/April/2018 for my 35th birthday!"
@@ -21,75 +19,100 @@ const genesisCoinbaseData = "The Times 15/April/2018 for my 35th birthday!"
 const (
 	defaultNonce = 0
 	blockDefaultDifficult = 20
-	MaxBlockBaseSize = 1000000
 )
 
 
 
 var ErrorBlockChainNotFount = errors.New("blockchain is not found")
+var ErrorBlockChainAlreadyExists = errors.New("blockchain already exists")
 var ErrorNoExistsAnyBlock = errors.New("not exists any block")
+var ErrorPrevBlockNotFound = errors.New("previous block not found in block index")
+
+// blockMsg is submitted to Blockchain.blockChan by ProcessBlock/MineBlock;
+// processor replies on reply with the outcome of connecting block.
+type blockMsg struct {
+	block *Block
+	reply chan error
+}
 
 // Blockchain implements interactions with a DB
 type Blockchain struct {
+	// lastBlockHashLock guards lastBlockHash: processor is its only
+	// writer, but Iterator (and everything built on it: FindUTXO,
+	// FindTransaction, GetBlockHashes, ListBlockHashs, ...) reads it from
+	// whatever goroutine calls them, concurrently with block acceptance.
+	lastBlockHashLock sync.RWMutex
 	lastBlockHash []byte
-	db  *bolt.DB
-	chainLock *sync.RWMutex
+	store storage.Store
+
+	// blockIndex tracks every known block, main chain or side branch, and
+	// is the authority for picking the branch with the most work.
+	blockIndex *BlockIndex
 
-	orphanLock   *sync.RWMutex
-	orphanBlocks map[hashx.Hash]*Block
+	orphans *OrphanManager
 
-	// previous hash index for faster lookups
-	prevOrphanBlocks map[hashx.Hash][]*Block
+	// txIndexEnabled reports whether connectBlock/disconnectBlock keep
+	// the tx index up to date. It is off by default since it costs a
+	// write per transaction; cli.startNode turns it on with --txindex.
+	txIndexEnabled bool
+
+	// blockChan serializes every incoming block through processor, which
+	// is the single writer of lastBlockHash and blockIndex.
+	blockChan chan *blockMsg
 
 	// when accept new block, it will stop current mining work
 	miningQuit chan struct{}
 }
 
-// CreateBlockchain creates a new blockchain with genesisBlock
-func CreateBlockchain(isGenesisNode bool, address, nodeID string) *Blockchain {
-	dbFile := storage.GetDBFileName(nodeID)
-	if util.ExitFile(dbFile) {
-		fmt.Println("Blockchain already exists.")
-		os.Exit(1)
-	}
-
+// CreateBlockchain creates a new blockchain with genesisBlock, persisted
+// through the named storage driver ("boltdb" or "memdb"). txIndex turns
+// on the transaction index, used by GetRawTransaction/FindTransaction to
+// look up a confirmed transaction without scanning the chain.
+func CreateBlockchain(isGenesisNode bool, address, driverName, nodeID string, txIndex bool) *Blockchain {
 	fmt.Println("CreateBlockchain Begin")
 
-
-	db, err := bolt.Open(dbFile, 0600, nil)
+	store, err := storage.Open(driverName, nodeID)
 	if err != nil {
-		log.Panic("Open db error", err)
+		log.Panic("storage.Open error", err)
 	}
 
-	//create bolt block bucket
-	err = storage.CreateBlockBucket(db)
-	if err != nil {
-		log.Panic("CreateBlockBucket error", err)
+	if existingHash, _, err := store.GetLastBlock(); err != nil {
+		log.Panic("GetLastBlock error", err)
+	} else if len(existingHash) > 0 {
+		fmt.Println("Blockchain already exists.")
+		log.Panic(ErrorBlockChainAlreadyExists)
 	}
 
 	var lastBlockHash []byte
 	if isGenesisNode {
 		genesis := NewGenesisBlock(address)
 
-		err =storage.SaveBlock(db, genesis.Hash, SerializeBlock(genesis))
+		err = store.SaveBlock(genesis.Hash, SerializeBlock(genesis))
 		if err != nil {
 			log.Panic("SaveBlock error", err)
 		}else{
 			lastBlockHash = genesis.Hash
+			if err = store.PutLastHash(lastBlockHash); err != nil {
+				log.Panic("PutLastHash error", err)
+			}
 		}
 	}
 
-
+	blockIndex, err := buildBlockIndex(store)
+	if err != nil {
+		log.Panic("buildBlockIndex error", err)
+	}
 
 	bc := Blockchain{
 		lastBlockHash:lastBlockHash,
-		db:db,
-		chainLock:new(sync.RWMutex),
-		orphanLock:new(sync.RWMutex),
-		orphanBlocks:make(map[hashx.Hash]*Block),
-		prevOrphanBlocks:make(map[hashx.Hash][]*Block),
+		store:store,
+		blockIndex:blockIndex,
+		orphans:NewOrphanManager(0),
+		txIndexEnabled:txIndex,
+		blockChan:make(chan *blockMsg),
 		miningQuit:make(chan struct{}),
 	}
+	go bc.processor()
 
 	fmt.Println("CreateBlockchain Success!")
 	fmt.Println(fmt.Sprintf("lastBlockHash %x", bc.lastBlockHash))
@@ -102,97 +125,353 @@ func CreateBlockchain(isGenesisNode bool, address, nodeID string) *Blockchain {
 	return &bc
 }
 
-// LoadBlockChain load Blockchain with nodeID from bolt
-func LoadBlockChain(nodeID string) (*Blockchain, error) {
-	dbFile := storage.GetDBFileName(nodeID)
-	if !util.ExitFile(dbFile) {
-		fmt.Println("No existing blockchain found. Create one first.")
-		return nil, ErrorBlockChainNotFount
+// LoadBlockChain loads an existing Blockchain for nodeID from the named
+// storage driver ("boltdb" or "memdb"). txIndex turns on the transaction
+// index; if the datastore already has main-chain blocks but no tx index
+// entries (i.e. it predates the index), LoadBlockChain runs
+// RebuildTxIndex once before returning.
+func LoadBlockChain(driverName, nodeID string, txIndex bool) (*Blockchain, error) {
+	store, err := storage.Open(driverName, nodeID)
+	if err != nil {
+		return nil, err
 	}
 
-	var db *bolt.DB
-	var err error
-
-	var lastBlockHash []byte
-	db, err = bolt.Open(dbFile, 0600, nil)
+	lastBlockHash, _, err := store.GetLastBlock()
 	if err != nil {
 		return nil, err
 	}
+	if len(lastBlockHash) == 0 {
+		fmt.Println("No existing blockchain found. Create one first.")
+		return nil, ErrorBlockChainNotFount
+	}
 
-	lastBlockHash, _, err = storage.GetLastBlock(db)
+	blockIndex, err := buildBlockIndex(store)
 	if err != nil {
 		return nil, err
 	}
 
 	bc := Blockchain{
 		lastBlockHash:lastBlockHash,
-		db:db,
-		chainLock:new(sync.RWMutex),
-		orphanLock:new(sync.RWMutex),
-		orphanBlocks:make(map[hashx.Hash]*Block),
-		prevOrphanBlocks:make(map[hashx.Hash][]*Block),
+		store:store,
+		blockIndex:blockIndex,
+		orphans:NewOrphanManager(0),
+		txIndexEnabled:txIndex,
+		blockChan:make(chan *blockMsg),
 		miningQuit:make(chan struct{}),
 	}
 
+	if txIndex {
+		predatesIndex, err := bc.datastoreHasBlocksButNoTxIndex()
+		if err != nil {
+			return nil, err
+		}
+		if predatesIndex {
+			if err := bc.RebuildTxIndex(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	go bc.processor()
+
 	return &bc, nil
 }
 
-// GetStorageDB get storage db
-func (bc *Blockchain) GetStorageDB() *bolt.DB {
-	return bc.db
+// datastoreHasBlocksButNoTxIndex reports whether store already has
+// main-chain blocks recorded but no tx index entries, i.e. txIndex was
+// just turned on for a datastore that predates it and needs a rebuild.
+func (bc *Blockchain) datastoreHasBlocksButNoTxIndex() (bool, error) {
+	hasBlocks := false
+	if err := bc.store.IterateHeightIndex(func(height int32, hash []byte) error {
+		hasBlocks = true
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	if !hasBlocks {
+		return false, nil
+	}
+
+	hasTxIndex := false
+	if err := bc.store.IterateTxIndex(func(txID, blockHash []byte, offset int32) error {
+		hasTxIndex = true
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	return !hasTxIndex, nil
+}
+
+// GetStore returns the Blockchain's underlying Store.
+func (bc *Blockchain) GetStore() storage.Store {
+	return bc.store
 }
 
-// addOrphanBlock add block into orphan blocks
-func (bc *Blockchain) addOrphanBlock(block *Block){
-	bc.orphanLock.Lock()
-	defer bc.orphanLock.Unlock()
-	bc.orphanBlocks[*block.GetHash()] = block
+// processor is the single goroutine allowed to mutate lastBlockHash and
+// blockIndex. Every inbound block, whether mined locally or received from
+// a peer, is funneled through blockChan so acceptance decisions never
+// race each other.
+func (bc *Blockchain) processor() {
+	for msg := range bc.blockChan {
+		msg.reply <- bc.acceptBlock(msg.block)
+	}
+}
 
-	// Add to previous hash index for faster lookups.
-	prevHash := block.GetPrevHash()
-	bc.prevOrphanBlocks[*prevHash] = append(bc.prevOrphanBlocks[*prevHash], block)
+// ProcessBlock submits block to the processor and waits for the result of
+// trying to connect it to the chain. It is safe to call concurrently from
+// many goroutines (inbound p2p blocks, the miner, ...).
+func (bc *Blockchain) ProcessBlock(block *Block) error {
+	reply := make(chan error, 1)
+	bc.blockChan <- &blockMsg{block: block, reply: reply}
+	return <-reply
 }
 
-// AddBlock add the block into the blockchain
-// save to bolt, update LastBlockHash
-func (bc *Blockchain) AddBlock(block *Block) {
-	err := bc.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(storage.BoltBlocksBucket))
-		blockInDb := b.Get(block.Hash)
-		if blockInDb != nil {
-			return nil
+// acceptBlock runs on the processor goroutine only. It connects block
+// onto the BlockIndex, performing a reorganization if block's branch
+// overtakes the current main chain, and then recursively accepts any
+// previously-orphaned descendants of block.
+//
+// Unlike height, which ties for competing miners, the block is accepted
+// onto whichever branch of the BlockIndex ends up with the most
+// cumulative work; if that branch is not the one currently extending the
+// main chain, a reorganization is performed.
+func (bc *Blockchain) acceptBlock(block *Block) error {
+	hash := block.GetHash()
+	if _, ok := bc.blockIndex.LookupNode(hash); ok {
+		// already known, nothing to do
+		return nil
+	}
+
+	prevHash := hashFromBytes(block.PrevBlockHash)
+	parent, ok := bc.blockIndex.LookupNode(&prevHash)
+	if !ok && len(block.PrevBlockHash) != 0 {
+		// unknown parent: stash as an orphan until it arrives
+		bc.orphans.Add(block)
+		return nil
+	}
+
+	if err := checkBlock(block, parent); err != nil {
+		return err
+	}
+
+	if err := bc.store.SaveBlock(block.Hash, SerializeBlock(block)); err != nil {
+		return err
+	}
+
+	node := newBlockNode(block, parent)
+	bc.blockIndex.AddNode(node)
+
+	if err := bc.tryExtendTip(node); err != nil {
+		return err
+	}
+
+	bc.acceptOrphansOf(*hash)
+	return nil
+}
+
+// tryExtendTip connects node onto the chain if its branch now carries more
+// work than the current tip, reorganizing if node's branch diverges from
+// the tip's.
+func (bc *Blockchain) tryExtendTip(node *BlockNode) error {
+	tip := bc.blockIndex.MainChainTip()
+	if tip != nil && node.workSum.Cmp(tip.workSum) <= 0 {
+		// extends a side branch that is still behind the main chain
+		return nil
+	}
+
+	if tip == nil || node.parent == tip {
+		return bc.extendMainChain(node)
+	}
+
+	fork := findFork(tip, node)
+	return bc.reorganize(fork, tip, node)
+}
+
+// acceptOrphansOf recursively connects every orphan waiting on hash, and
+// in turn every orphan waiting on those, now that hash is on the chain.
+func (bc *Blockchain) acceptOrphansOf(hash hashx.Hash) {
+	for _, orphan := range bc.orphans.GetByPrev(hash) {
+		bc.orphans.Remove(*orphan.GetHash())
+		if err := bc.acceptBlock(orphan); err != nil {
+			logx.Errorf("failed to connect orphan descendant", orphan.GetHash().String(), err)
+			continue
 		}
+		bc.acceptOrphansOf(*orphan.GetHash())
+	}
+}
 
-		blockData := SerializeBlock(block)
-		err := b.Put(block.Hash, blockData)
-		if err != nil {
-			log.Panic(err)
+// extendMainChain appends node directly to the current tip; this is the
+// common case where the new block simply continues the winning branch.
+func (bc *Blockchain) extendMainChain(node *BlockNode) error {
+	if err := bc.connectBlock(node); err != nil {
+		return err
+	}
+
+	bc.blockIndex.setTip(node)
+	hash := node.hash.CloneBytes()
+	bc.setLastBlockHash(hash)
+	return bc.store.PutLastHash(hash)
+}
+
+// reorganize switches the main chain from the branch ending at oldTip to
+// the branch ending at newTip, both of which fork from fork. Blocks from
+// oldTip down to (but not including) fork are disconnected in reverse
+// order; blocks from fork up to newTip are connected in forward order.
+//
+// The UTXO set has no incremental "undo" bookkeeping, so disconnectBlock
+// doesn't touch it; once every block is detached, UTXOSet.Reverse rebuilds
+// it a single time against the (now current) fork point, an O(chain
+// length) cost paid once per reorg regardless of fork depth, before the
+// attach blocks are connected back on top incrementally.
+func (bc *Blockchain) reorganize(fork, oldTip, newTip *BlockNode) error {
+	var detach, attach []*BlockNode
+	for n := oldTip; n != fork; n = n.parent {
+		detach = append(detach, n)
+	}
+	for n := newTip; n != fork; n = n.parent {
+		attach = append([]*BlockNode{n}, attach...)
+	}
+
+	for _, n := range detach {
+		if err := bc.disconnectBlock(n); err != nil {
+			return err
 		}
+		bc.blockIndex.unsetMainChain(n)
+	}
 
-		var bestHeight int32
-		lastHash := b.Get([]byte(storage.BoltLastHashKey))
-		lastBlockData := b.Get(lastHash)
-		if lastBlockData == nil{
-			bestHeight = 0
-		}else{
-			lastBlock := DeserializeBlock(lastBlockData)
-			bestHeight = lastBlock.Height
+	if len(detach) > 0 {
+		bc.GetUTXOSet().Reverse()
+	}
+
+	for _, n := range attach {
+		if err := bc.connectBlock(n); err != nil {
+			return err
 		}
+	}
 
+	bc.blockIndex.setTip(newTip)
+	hash := newTip.hash.CloneBytes()
+	bc.setLastBlockHash(hash)
+	return bc.store.PutLastHash(hash)
+}
 
-		if block.Height >= bestHeight {
-			err = b.Put([]byte(storage.BoltLastHashKey), block.Hash)
-			if err != nil {
-				log.Panic(err)
-			}
-			bc.lastBlockHash = block.Hash
+// connectBlock applies node's block to the UTXO set and records it as a
+// main-chain block at its height.
+func (bc *Blockchain) connectBlock(node *BlockNode) error {
+	block, err := bc.GetBlock(node.hash.CloneBytes())
+	if err != nil {
+		return err
+	}
+
+	bc.GetUTXOSet().Update(block)
+
+	if bc.txIndexEnabled {
+		if err := bc.indexBlockTransactions(block); err != nil {
+			return err
 		}
+	}
 
-		return nil
+	return bc.store.PutMainChainHash(node.height, node.hash.CloneBytes())
+}
+
+// disconnectBlock removes node's main-chain height record and, if the tx
+// index is on, unindexes its transactions, used while detaching blocks
+// during a reorg. It does not touch the UTXO set: reorganize rebuilds
+// that once, after every block in the reorg has been detached, rather
+// than paying a full rebuild per disconnected block.
+func (bc *Blockchain) disconnectBlock(node *BlockNode) error {
+	block, err := bc.GetBlock(node.hash.CloneBytes())
+	if err != nil {
+		return err
+	}
+
+	if node.parent != nil {
+		bc.setLastBlockHash(node.parent.hash.CloneBytes())
+	} else {
+		bc.setLastBlockHash(nil)
+	}
+
+	if bc.txIndexEnabled {
+		bc.unindexBlockTransactions(block)
+	}
+
+	return bc.store.DeleteMainChainHash(node.height)
+}
+
+// indexBlockTransactions records block's transactions in the tx index,
+// keyed by transaction ID, so GetRawTransaction/FindTransaction can find
+// them without scanning the chain.
+func (bc *Blockchain) indexBlockTransactions(block *Block) error {
+	blockHash := block.GetHash().CloneBytes()
+	for offset, tx := range block.Transactions {
+		if err := bc.store.PutTxIndex(tx.ID.CloneBytes(), blockHash, int32(offset)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unindexBlockTransactions removes block's transactions from the tx
+// index, used while detaching block during a reorg. A failed delete is
+// logged rather than aborting disconnectBlock; the stale entry keeps
+// pointing at the detached block, and GetRawTransaction does not check
+// whether that block is still on the main chain.
+func (bc *Blockchain) unindexBlockTransactions(block *Block) {
+	for _, tx := range block.Transactions {
+		if err := bc.store.DeleteTxIndex(tx.ID.CloneBytes()); err != nil {
+			logx.Errorf("unindexBlockTransactions: DeleteTxIndex failed", tx.StringID(), err)
+		}
+	}
+}
+
+// RebuildTxIndex repopulates the tx index from every main-chain block
+// already on disk. LoadBlockChain calls this once when txIndex is turned
+// on for a datastore that predates the index.
+func (bc *Blockchain) RebuildTxIndex() error {
+	if err := bc.store.ClearTxIndex(); err != nil {
+		return err
+	}
+
+	return bc.store.IterateHeightIndex(func(height int32, hash []byte) error {
+		block, err := bc.GetBlock(hash)
+		if err != nil {
+			return err
+		}
+		return bc.indexBlockTransactions(block)
 	})
+}
+
+// GetRawTransaction looks up a confirmed transaction directly via the tx
+// index, without scanning the chain, and returns it along with the hash
+// of the block that contains it.
+func (bc *Blockchain) GetRawTransaction(id *hashx.Hash) (*Transaction, *hashx.Hash, error) {
+	blockHash, offset, err := bc.store.GetTxIndex(id.CloneBytes())
 	if err != nil {
-		log.Panic(err)
+		return nil, nil, err
+	}
+	if len(blockHash) == 0 {
+		return nil, nil, ErrorNotFoundTransaction
+	}
+
+	block, err := bc.GetBlock(blockHash)
+	if err != nil {
+		return nil, nil, err
 	}
+
+	if int(offset) < len(block.Transactions) && block.Transactions[offset].ID.IsEqual(id) {
+		return block.Transactions[offset], block.GetHash(), nil
+	}
+
+	// the recorded offset is stale, e.g. a reorg raced this lookup; fall
+	// back to scanning the block rather than trusting it blindly
+	for _, tx := range block.Transactions {
+		if tx.ID.IsEqual(id) {
+			return tx, block.GetHash(), nil
+		}
+	}
+
+	return nil, nil, ErrorNotFoundTransaction
 }
 
 // HaveBlock check block hash exists
@@ -212,7 +491,7 @@ func (bc *Blockchain) HaveBlock(blockHash *hashx.Hash) (bool, error){
 // GetBlock finds a block by its hash and returns it
 func (bc *Blockchain) GetBlock(blockHash []byte) (*Block, error) {
 	var block *Block
-	blockData, err := storage.GetBlock(bc.db, blockHash)
+	blockData, err := bc.store.GetBlock(blockHash)
 	if err != nil{
 		return nil, err
 	}
@@ -227,14 +506,21 @@ func (bc *Blockchain) MineBlock(transactions []*Transaction) (*Block, bool) {
 	var lastHeight int32
 	var err error
 
+	validTxs := make([]*Transaction, 0, len(transactions))
 	for _, tx := range transactions {
-		// TODO: ignore transaction if it's not valid
-		if bc.VerifyTransaction(tx) != true {
-			log.Panic("ERROR: Invalid transaction")
+		if err := validation.CheckTransactionSanity(txSummaryOf(tx)); err != nil {
+			logx.Errorf("MineBlock: skipping malformed tx", tx.StringID(), err)
+			continue
+		}
+		if !bc.VerifyTransaction(tx) {
+			logx.Errorf("MineBlock: skipping unverifiable tx", tx.StringID())
+			continue
 		}
+		validTxs = append(validTxs, tx)
 	}
+	transactions = validTxs
 
-	lastHash, lastBlockData, err = storage.GetLastBlock(bc.db)
+	lastHash, lastBlockData, err = bc.store.GetLastBlock()
 	if err != nil{
 		log.Panic(err)
 	}
@@ -246,14 +532,11 @@ func (bc *Blockchain) MineBlock(transactions []*Transaction) (*Block, bool) {
 	if !isSolve{
 		logx.Infof("MineBlock failed", lastHash, lastHeight)
 	}else{
-		//save block to db
-		err = storage.SaveBlock(bc.db, newBlock.Hash, SerializeBlock(newBlock))
-		if err != nil {
-			//log.Panic(err)
+		//save block, update BlockIndex and lastBlockHash
+		if err = bc.ProcessBlock(newBlock); err != nil {
 			logx.Errorf("MineBlock error", lastHash, lastHeight, err)
 			return nil, false
 		}
-		bc.lastBlockHash = newBlock.Hash
 	}
 	return newBlock, isSolve
 }
@@ -344,8 +627,17 @@ func (bc *Blockchain) SignTransaction(tx *Transaction, privKey ecdsa.PrivateKey)
 	tx.Sign(privKey, prevTXs)
 }
 
-// FindTransaction finds a transaction by its ID
+// FindTransaction finds a transaction by its ID, consulting the tx index
+// first when one is maintained; it only falls back to scanning every
+// block when the index is off or doesn't have ID, e.g. a mempool
+// transaction that hasn't been mined yet.
 func (bc *Blockchain) FindTransaction(ID *hashx.Hash) (*Transaction, error) {
+	if bc.txIndexEnabled {
+		if tx, _, err := bc.GetRawTransaction(ID); err == nil {
+			return tx, nil
+		}
+	}
+
 	bci := bc.Iterator()
 
 	for {
@@ -399,7 +691,7 @@ func (bc *Blockchain) GetBalance(address string) int{
 // GetBestHeight returns the height of the latest block
 func (bc *Blockchain) GetBestHeight() int32 {
 	var lastBlock *Block
-	_, lastBlockData, err := storage.GetLastBlock(bc.db)
+	_, lastBlockData, err := bc.store.GetLastBlock()
 	if err != nil{
 		return 0
 	}
@@ -416,7 +708,7 @@ func (bc *Blockchain) GetBestHeight() int32 {
 // GetLastBlock returns the latest block
 func (bc *Blockchain) GetLastBlock() (*Block, error){
 	var lastBlock *Block
-	_, lastBlockData, err := storage.GetLastBlock(bc.db)
+	_, lastBlockData, err := bc.store.GetLastBlock()
 	if err != nil{
 		return nil, err
 	}
@@ -468,10 +760,29 @@ func (bc *Blockchain) GetBlockHashes(beginHash *hashx.Hash, stopHash hashx.Hash,
 
 // Iterator returns a BlockchainIterator
 func (bc *Blockchain) Iterator() *BlockchainIterator {
-	bci := &BlockchainIterator{bc.lastBlockHash, bc.db}
+	bci := &BlockchainIterator{bc.getLastBlockHash(), bc.store}
 
 	return bci
 }
 
+// setLastBlockHash records hash as the chain tip. Only the processor
+// goroutine calls this, but lastBlockHashLock still guards it since
+// getLastBlockHash is read concurrently from any caller of Iterator.
+func (bc *Blockchain) setLastBlockHash(hash []byte) {
+	bc.lastBlockHashLock.Lock()
+	defer bc.lastBlockHashLock.Unlock()
+
+	bc.lastBlockHash = hash
+}
+
+// getLastBlockHash returns the chain tip recorded by the processor
+// goroutine, safe to call concurrently with block acceptance.
+func (bc *Blockchain) getLastBlockHash() []byte {
+	bc.lastBlockHashLock.RLock()
+	defer bc.lastBlockHashLock.RUnlock()
+
+	return bc.lastBlockHash
+}
+
 
 
@@ -0,0 +1,264 @@
+package chain
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/michain/dotcoin/storage"
+	"github.com/michain/dotcoin/util/hashx"
+)
+
+// oneLsh256 is 2^256, used as the numerator when turning a block's
+// difficulty bits into a work value.
+var oneLsh256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// BlockNode is a node in the in-memory block index. It mirrors the on-disk
+// block just enough to make chain-selection decisions (cumulative work,
+// ancestry) without having to deserialize the full block from storage.
+type BlockNode struct {
+	parent    *BlockNode
+	hash      hashx.Hash
+	height    int32
+	timestamp int64
+	bits      int64
+	workSum   *big.Int
+
+	// mainChain is true when this node is currently part of the main chain.
+	mainChain bool
+}
+
+// newBlockNode builds a BlockNode for block, linking it to parent.
+// parent may be nil only for the genesis block.
+func newBlockNode(block *Block, parent *BlockNode) *BlockNode {
+	node := &BlockNode{
+		parent:    parent,
+		hash:      *block.GetHash(),
+		height:    block.Height,
+		timestamp: block.Timestamp,
+		bits:      block.Bits,
+	}
+
+	work := calcWork(block.Bits)
+	if parent != nil {
+		node.workSum = new(big.Int).Add(parent.workSum, work)
+	} else {
+		node.workSum = work
+	}
+
+	return node
+}
+
+// calcWork converts PoW difficulty bits into a work value, following the
+// same "more leading zero bits -> more work" relation used by the miner:
+// work = 2^256 / (target+1).
+func calcWork(bits int64) *big.Int {
+	if bits <= 0 {
+		return big.NewInt(0)
+	}
+
+	target := new(big.Int).Lsh(big.NewInt(1), uint(256-bits))
+	denominator := new(big.Int).Add(target, big.NewInt(1))
+
+	return new(big.Int).Div(oneLsh256, denominator)
+}
+
+// BlockIndex is an in-memory index of every known block, main chain or
+// side branch, keyed by block hash. It is the authority for deciding which
+// branch has the most cumulative work and is therefore the main chain.
+type BlockIndex struct {
+	lock sync.RWMutex
+
+	index          map[hashx.Hash]*BlockNode
+	mainChainNodes map[int32]*BlockNode
+	tip            *BlockNode
+}
+
+// NewBlockIndex creates an empty BlockIndex.
+func NewBlockIndex() *BlockIndex {
+	return &BlockIndex{
+		index:          make(map[hashx.Hash]*BlockNode),
+		mainChainNodes: make(map[int32]*BlockNode),
+	}
+}
+
+// AddNode registers node in the index. It does not change the main chain;
+// callers decide that separately via setTip.
+func (bi *BlockIndex) AddNode(node *BlockNode) {
+	bi.lock.Lock()
+	defer bi.lock.Unlock()
+
+	bi.index[node.hash] = node
+}
+
+// LookupNode returns the node for hash, if known.
+func (bi *BlockIndex) LookupNode(hash *hashx.Hash) (*BlockNode, bool) {
+	bi.lock.RLock()
+	defer bi.lock.RUnlock()
+
+	node, ok := bi.index[*hash]
+	return node, ok
+}
+
+// NodeByHeight returns the main-chain node at height h, if any.
+func (bi *BlockIndex) NodeByHeight(h int32) (*BlockNode, bool) {
+	bi.lock.RLock()
+	defer bi.lock.RUnlock()
+
+	node, ok := bi.mainChainNodes[h]
+	return node, ok
+}
+
+// MainChainTip returns the node currently at the tip of the main chain.
+// It returns nil if the index is empty.
+func (bi *BlockIndex) MainChainTip() *BlockNode {
+	bi.lock.RLock()
+	defer bi.lock.RUnlock()
+
+	return bi.tip
+}
+
+// setTip marks the chain rooted at tip as the main chain, walking back all
+// the way to the genesis block to populate mainChainNodes and the
+// mainChain flag.
+func (bi *BlockIndex) setTip(tip *BlockNode) {
+	bi.lock.Lock()
+	defer bi.lock.Unlock()
+
+	for n := tip; n != nil; n = n.parent {
+		n.mainChain = true
+		bi.mainChainNodes[n.height] = n
+	}
+	bi.tip = tip
+}
+
+// unsetMainChain clears the mainChain flag and height lookup for node,
+// used while detaching blocks during a reorganization.
+func (bi *BlockIndex) unsetMainChain(node *BlockNode) {
+	bi.lock.Lock()
+	defer bi.lock.Unlock()
+
+	node.mainChain = false
+	if bi.mainChainNodes[node.height] == node {
+		delete(bi.mainChainNodes, node.height)
+	}
+}
+
+// AncestorOf walks node's parent pointers back to height h and returns the
+// ancestor found there. It returns nil if h is negative or above node's
+// own height.
+func AncestorOf(node *BlockNode, h int32) *BlockNode {
+	if node == nil || h < 0 || h > node.height {
+		return nil
+	}
+
+	n := node
+	for n.height > h {
+		n = n.parent
+	}
+	return n
+}
+
+// findFork walks back from a and b until they meet at a common ancestor,
+// which is returned. It returns nil if the two nodes share no ancestor
+// (i.e. they belong to different geneses).
+func findFork(a, b *BlockNode) *BlockNode {
+	if a == nil || b == nil {
+		return nil
+	}
+
+	for a.height > b.height {
+		a = a.parent
+	}
+	for b.height > a.height {
+		b = b.parent
+	}
+	for a != b {
+		if a == nil || b == nil {
+			return nil
+		}
+		a = a.parent
+		b = b.parent
+	}
+	return a
+}
+
+// hashFromBytes copies raw block-hash bytes into a hashx.Hash value.
+func hashFromBytes(b []byte) hashx.Hash {
+	var h hashx.Hash
+	copy(h[:], b)
+	return h
+}
+
+// buildBlockIndex reconstructs a BlockIndex by scanning every block stored
+// in store. It is run once at startup; afterwards the index is maintained
+// incrementally by acceptBlock/reorganize.
+func buildBlockIndex(store storage.Store) (*BlockIndex, error) {
+	blocks := make(map[hashx.Hash]*Block)
+
+	err := store.IterateBlocks(func(hash, data []byte) error {
+		block := DeserializeBlock(data)
+		blocks[*block.GetHash()] = block
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bi := NewBlockIndex()
+	nodes := make(map[hashx.Hash]*BlockNode)
+
+	// Blocks can be visited in any order, so repeatedly sweep the set
+	// until every block whose parent is already indexed has been added.
+	for progress := true; progress && len(nodes) < len(blocks); {
+		progress = false
+		for hash, block := range blocks {
+			if _, done := nodes[hash]; done {
+				continue
+			}
+
+			if len(block.PrevBlockHash) == 0 {
+				node := newBlockNode(block, nil)
+				nodes[hash] = node
+				bi.AddNode(node)
+				progress = true
+				continue
+			}
+
+			if parent, ok := nodes[hashFromBytes(block.PrevBlockHash)]; ok {
+				node := newBlockNode(block, parent)
+				nodes[hash] = node
+				bi.AddNode(node)
+				progress = true
+			}
+		}
+	}
+
+	var tip *BlockNode
+	for _, node := range nodes {
+		if tip == nil || node.workSum.Cmp(tip.workSum) > 0 {
+			tip = node
+		}
+	}
+	if tip == nil {
+		return bi, nil
+	}
+
+	bi.setTip(tip)
+	if err := persistMainChain(store, tip); err != nil {
+		return nil, err
+	}
+
+	return bi, nil
+}
+
+// persistMainChain writes the main-chain hash for every block from tip
+// back to genesis into the height index bucket.
+func persistMainChain(store storage.Store, tip *BlockNode) error {
+	for n := tip; n != nil; n = n.parent {
+		hash := n.hash
+		if err := store.PutMainChainHash(n.height, hash.CloneBytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
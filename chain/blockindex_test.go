@@ -0,0 +1,129 @@
+package chain
+
+import (
+	"math/big"
+	"testing"
+)
+
+// chainOf builds a linear run of n BlockNodes on top of parent, at
+// consecutive heights starting right after parent's height (or at 0 if
+// parent is nil), each with workSum 1 more than its parent. It returns the
+// nodes in ascending height order.
+func chainOf(parent *BlockNode, n int) []*BlockNode {
+	nodes := make([]*BlockNode, n)
+	prev := parent
+	for i := 0; i < n; i++ {
+		height := int32(i)
+		workSum := big.NewInt(int64(i + 1))
+		if prev != nil {
+			height = prev.height + 1
+			workSum = new(big.Int).Add(prev.workSum, big.NewInt(1))
+		}
+		node := &BlockNode{parent: prev, height: height, workSum: workSum}
+		node.hash[0] = byte(height)
+		nodes[i] = node
+		prev = node
+	}
+	return nodes
+}
+
+func TestFindForkCommonAncestor(t *testing.T) {
+	// genesis -> 1 -> 2 -> (3a / 3b), a fork at height 3.
+	trunk := chainOf(nil, 3)
+	branchA := chainOf(trunk[2], 2)
+	branchB := chainOf(trunk[2], 1)
+
+	fork := findFork(branchA[len(branchA)-1], branchB[len(branchB)-1])
+	if fork != trunk[2] {
+		t.Fatalf("findFork = %v, want the height-2 node both branches share", fork)
+	}
+}
+
+func TestFindForkEqualNodes(t *testing.T) {
+	trunk := chainOf(nil, 1)
+	if got := findFork(trunk[0], trunk[0]); got != trunk[0] {
+		t.Fatalf("findFork(n, n) = %v, want n", got)
+	}
+}
+
+func TestFindForkDifferentGenesis(t *testing.T) {
+	a := chainOf(nil, 2)
+	b := chainOf(nil, 2)
+
+	if got := findFork(a[1], b[1]); got != nil {
+		t.Fatalf("findFork across unrelated geneses = %v, want nil", got)
+	}
+}
+
+func TestFindForkNilArgument(t *testing.T) {
+	trunk := chainOf(nil, 1)
+	if got := findFork(nil, trunk[0]); got != nil {
+		t.Fatalf("findFork(nil, n) = %v, want nil", got)
+	}
+}
+
+func TestAncestorOf(t *testing.T) {
+	trunk := chainOf(nil, 5)
+	tip := trunk[4]
+
+	for h := int32(0); h < 5; h++ {
+		got := AncestorOf(tip, h)
+		if got != trunk[h] {
+			t.Fatalf("AncestorOf(tip, %d) = %v, want %v", h, got, trunk[h])
+		}
+	}
+}
+
+func TestAncestorOfOutOfRange(t *testing.T) {
+	trunk := chainOf(nil, 3)
+	tip := trunk[2]
+
+	if got := AncestorOf(tip, -1); got != nil {
+		t.Fatalf("AncestorOf(tip, -1) = %v, want nil", got)
+	}
+	if got := AncestorOf(tip, tip.height+1); got != nil {
+		t.Fatalf("AncestorOf(tip, height+1) = %v, want nil", got)
+	}
+	if got := AncestorOf(nil, 0); got != nil {
+		t.Fatalf("AncestorOf(nil, 0) = %v, want nil", got)
+	}
+}
+
+func TestBlockIndexReorgSwitchesMainChain(t *testing.T) {
+	bi := NewBlockIndex()
+
+	// genesis -> 1 -> 2, main chain to start.
+	trunk := chainOf(nil, 3)
+	for _, n := range trunk {
+		bi.AddNode(n)
+	}
+	bi.setTip(trunk[2])
+
+	// A higher-work side branch forking after height 1.
+	side := chainOf(trunk[1], 3)
+	for _, n := range side {
+		bi.AddNode(n)
+		n.workSum.Add(n.workSum, big.NewInt(10))
+	}
+	newTip := side[2]
+
+	fork := findFork(bi.MainChainTip(), newTip)
+	if fork != trunk[1] {
+		t.Fatalf("findFork = %v, want the height-1 node", fork)
+	}
+
+	for n := trunk[2]; n != fork; n = n.parent {
+		bi.unsetMainChain(n)
+	}
+	bi.setTip(newTip)
+
+	if bi.MainChainTip() != newTip {
+		t.Fatalf("MainChainTip = %v, want %v", bi.MainChainTip(), newTip)
+	}
+	if got, ok := bi.NodeByHeight(2); !ok || got != side[0] {
+		t.Fatalf("NodeByHeight(2) = %v, %v, want the side-branch node", got, ok)
+	}
+	if got, ok := bi.NodeByHeight(4); !ok || got != newTip {
+		t.Fatalf("NodeByHeight(4) = %v, %v, want the new tip", got, ok)
+	}
+}
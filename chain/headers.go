@@ -0,0 +1,109 @@
+package chain
+
+import "github.com/michain/dotcoin/util/hashx"
+
+// locatorStepStart is how many immediate-ancestor hashes BlockLocator
+// includes before it starts doubling the step between entries.
+const locatorStepStart = 10
+
+// BlockHeader carries just the fields a peer needs to validate a chain of
+// block headers before fetching the full blocks, mirroring the subset of
+// Block that makes up its proof-of-work linkage.
+type BlockHeader struct {
+	Hash          []byte
+	PrevBlockHash []byte
+	Height        int32
+	Timestamp     int64
+	Bits          int64
+}
+
+// Header extracts b's header.
+func (b *Block) Header() *BlockHeader {
+	return &BlockHeader{
+		Hash:          b.Hash,
+		PrevBlockHash: b.PrevBlockHash,
+		Height:        b.Height,
+		Timestamp:     b.Timestamp,
+		Bits:          b.Bits,
+	}
+}
+
+// BlockLocator builds a Bitcoin-style block locator starting at tip (or
+// at the current main-chain tip if tip is nil): the ten most recent
+// hashes, then hashes at exponentially doubling distances, always ending
+// with genesis. A peer walks this list to find the newest block it also
+// has, even if it's sitting on a different branch than we are.
+func (bc *Blockchain) BlockLocator(tip *hashx.Hash) []*hashx.Hash {
+	start := bc.blockIndex.MainChainTip()
+	if tip != nil {
+		if n, ok := bc.blockIndex.LookupNode(tip); ok {
+			start = n
+		}
+	}
+	if start == nil {
+		return nil
+	}
+
+	var locator []*hashx.Hash
+	step := int32(1)
+	for node := start; node != nil; {
+		hash := node.hash
+		locator = append(locator, &hash)
+
+		if node.height == 0 {
+			break
+		}
+
+		if len(locator) >= locatorStepStart {
+			step *= 2
+		}
+
+		height := node.height - step
+		if height < 0 {
+			height = 0
+		}
+		node = AncestorOf(node, height)
+	}
+
+	return locator
+}
+
+// LocateHeaders finds the first hash in locator that is on the main
+// chain (falling back to genesis if none match) and returns up to
+// maxHeaders headers forward from there, stopping early at stopHash if
+// it is reached first.
+func (bc *Blockchain) LocateHeaders(locator []*hashx.Hash, stopHash *hashx.Hash, maxHeaders int) []*BlockHeader {
+	var start *BlockNode
+	for _, h := range locator {
+		if n, ok := bc.blockIndex.LookupNode(h); ok && n.mainChain {
+			start = n
+			break
+		}
+	}
+	if start == nil {
+		start, _ = bc.blockIndex.NodeByHeight(0)
+	}
+	if start == nil {
+		return nil
+	}
+
+	headers := make([]*BlockHeader, 0, maxHeaders)
+	for height := start.height + 1; len(headers) < maxHeaders; height++ {
+		node, ok := bc.blockIndex.NodeByHeight(height)
+		if !ok {
+			break
+		}
+
+		block, err := bc.GetBlock(node.hash.CloneBytes())
+		if err != nil {
+			break
+		}
+		headers = append(headers, block.Header())
+
+		if stopHash != nil && node.hash.IsEqual(stopHash) {
+			break
+		}
+	}
+
+	return headers
+}
@@ -0,0 +1,38 @@
+package chain
+
+import "github.com/michain/dotcoin/storage"
+
+// BlockchainIterator walks a chain of blocks backwards from currentHash to
+// genesis, reading each block from store.
+type BlockchainIterator struct {
+	currentHash []byte
+	store storage.Store
+}
+
+// Next returns the block at the iterator's current position and moves the
+// iterator to that block's parent. It returns nil once there is nothing
+// left to iterate.
+func (i *BlockchainIterator) Next() *Block {
+	if len(i.currentHash) == 0 {
+		return nil
+	}
+
+	data, err := i.store.GetBlock(i.currentHash)
+	if err != nil {
+		return nil
+	}
+
+	block := DeserializeBlock(data)
+	i.currentHash = block.PrevBlockHash
+	return block
+}
+
+// LocationHash repositions the iterator to start from hash, which must
+// already exist in store.
+func (i *BlockchainIterator) LocationHash(hash []byte) error {
+	if _, err := i.store.GetBlock(hash); err != nil {
+		return err
+	}
+	i.currentHash = hash
+	return nil
+}
@@ -0,0 +1,141 @@
+package chain
+
+import (
+	"sync"
+	"time"
+
+	"github.com/michain/dotcoin/util/hashx"
+)
+
+// defaultMaxOrphans bounds how many orphan blocks OrphanManager will hold
+// at once; once full, the oldest orphan is evicted to make room for a new
+// one, so a flood of unconnectable blocks can't grow memory unbounded.
+const defaultMaxOrphans = 100
+
+// orphanEntry wraps an orphan block with the time it was added, used by
+// Expire to age out orphans whose parent never shows up.
+type orphanEntry struct {
+	block   *Block
+	addedAt time.Time
+}
+
+// OrphanManager holds blocks that arrived before their parent, indexed both
+// by their own hash and by the hash they're waiting on, so a newly
+// connected block can look up and accept its waiting descendants.
+type OrphanManager struct {
+	lock sync.RWMutex
+
+	maxOrphans int
+	orphans    map[hashx.Hash]*orphanEntry
+	byPrev     map[hashx.Hash][]*Block
+
+	// oldest tracks insertion order so Add can evict the oldest orphan
+	// once maxOrphans is reached.
+	oldest []hashx.Hash
+}
+
+// NewOrphanManager creates an OrphanManager that holds at most maxOrphans
+// blocks at a time. A maxOrphans <= 0 falls back to defaultMaxOrphans.
+func NewOrphanManager(maxOrphans int) *OrphanManager {
+	if maxOrphans <= 0 {
+		maxOrphans = defaultMaxOrphans
+	}
+
+	return &OrphanManager{
+		maxOrphans: maxOrphans,
+		orphans:    make(map[hashx.Hash]*orphanEntry),
+		byPrev:     make(map[hashx.Hash][]*Block),
+	}
+}
+
+// Add stores block as an orphan, evicting the oldest orphan first if the
+// manager is already at capacity.
+func (om *OrphanManager) Add(block *Block) {
+	om.lock.Lock()
+	defer om.lock.Unlock()
+
+	hash := *block.GetHash()
+	if _, exists := om.orphans[hash]; exists {
+		return
+	}
+
+	if len(om.orphans) >= om.maxOrphans {
+		om.removeOldestLocked()
+	}
+
+	om.orphans[hash] = &orphanEntry{block: block, addedAt: time.Now()}
+	prevHash := *block.GetPrevHash()
+	om.byPrev[prevHash] = append(om.byPrev[prevHash], block)
+	om.oldest = append(om.oldest, hash)
+}
+
+// Remove drops the orphan with the given hash, if present.
+func (om *OrphanManager) Remove(hash hashx.Hash) {
+	om.lock.Lock()
+	defer om.lock.Unlock()
+
+	om.removeLocked(hash)
+}
+
+// GetByPrev returns every orphan currently waiting on prevHash.
+func (om *OrphanManager) GetByPrev(prevHash hashx.Hash) []*Block {
+	om.lock.RLock()
+	defer om.lock.RUnlock()
+
+	blocks := om.byPrev[prevHash]
+	out := make([]*Block, len(blocks))
+	copy(out, blocks)
+	return out
+}
+
+// Expire removes every orphan older than maxAge.
+func (om *OrphanManager) Expire(maxAge time.Duration) {
+	om.lock.Lock()
+	defer om.lock.Unlock()
+
+	cutoff := time.Now().Add(-maxAge)
+	for hash, entry := range om.orphans {
+		if entry.addedAt.Before(cutoff) {
+			om.removeLocked(hash)
+		}
+	}
+}
+
+// removeOldestLocked evicts the longest-held orphan. Callers must hold
+// om.lock.
+func (om *OrphanManager) removeOldestLocked() {
+	if len(om.oldest) == 0 {
+		return
+	}
+	om.removeLocked(om.oldest[0])
+}
+
+// removeLocked removes the orphan with the given hash from both indexes,
+// and from oldest so the FIFO slice never outlives the orphan it tracks.
+// Callers must hold om.lock.
+func (om *OrphanManager) removeLocked(hash hashx.Hash) {
+	entry, ok := om.orphans[hash]
+	if !ok {
+		return
+	}
+	delete(om.orphans, hash)
+
+	for i, h := range om.oldest {
+		if h == hash {
+			om.oldest = append(om.oldest[:i], om.oldest[i+1:]...)
+			break
+		}
+	}
+
+	prevHash := *entry.block.GetPrevHash()
+	siblings := om.byPrev[prevHash]
+	for i, b := range siblings {
+		if *b.GetHash() == hash {
+			om.byPrev[prevHash] = append(siblings[:i], siblings[i+1:]...)
+			break
+		}
+	}
+	if len(om.byPrev[prevHash]) == 0 {
+		delete(om.byPrev, prevHash)
+	}
+}
@@ -0,0 +1,110 @@
+package chain
+
+import (
+	"testing"
+	"time"
+)
+
+// orphanBlock builds a minimal Block whose hash and prev-hash are each a
+// single distinguishing byte, enough for OrphanManager's hash-keyed
+// bookkeeping without needing a real, minable block.
+func orphanBlock(hash, prevHash byte) *Block {
+	return &Block{Hash: []byte{hash}, PrevBlockHash: []byte{prevHash}}
+}
+
+func TestOrphanManagerAddAndGetByPrev(t *testing.T) {
+	om := NewOrphanManager(10)
+
+	b1 := orphanBlock(1, 0)
+	b2 := orphanBlock(2, 0)
+	om.Add(b1)
+	om.Add(b2)
+
+	waiting := om.GetByPrev(*orphanBlock(0, 0).GetHash())
+	if len(waiting) != 2 {
+		t.Fatalf("GetByPrev returned %d orphans, want 2", len(waiting))
+	}
+}
+
+func TestOrphanManagerRemove(t *testing.T) {
+	om := NewOrphanManager(10)
+
+	b1 := orphanBlock(1, 0)
+	om.Add(b1)
+	om.Remove(*b1.GetHash())
+
+	if waiting := om.GetByPrev(*orphanBlock(0, 0).GetHash()); len(waiting) != 0 {
+		t.Fatalf("GetByPrev after Remove = %d orphans, want 0", len(waiting))
+	}
+	if len(om.oldest) != 0 {
+		t.Fatalf("oldest after Remove = %v, want empty", om.oldest)
+	}
+}
+
+func TestOrphanManagerEvictsOldestOnceFull(t *testing.T) {
+	om := NewOrphanManager(2)
+
+	b1 := orphanBlock(1, 0)
+	b2 := orphanBlock(2, 0)
+	b3 := orphanBlock(3, 0)
+
+	om.Add(b1)
+	om.Add(b2)
+	om.Add(b3) // evicts b1, the oldest
+
+	if _, ok := om.orphans[*b1.GetHash()]; ok {
+		t.Fatalf("b1 should have been evicted once the manager hit capacity")
+	}
+	if _, ok := om.orphans[*b2.GetHash()]; !ok {
+		t.Fatalf("b2 should still be held")
+	}
+	if _, ok := om.orphans[*b3.GetHash()]; !ok {
+		t.Fatalf("b3 should still be held")
+	}
+	if len(om.orphans) != 2 {
+		t.Fatalf("orphans holds %d entries, want 2 (maxOrphans)", len(om.orphans))
+	}
+}
+
+func TestOrphanManagerOldestStaysBoundedOnNormalRemoval(t *testing.T) {
+	om := NewOrphanManager(5)
+
+	// Add and remove many more orphans than the manager's capacity, the
+	// way acceptBlock's normal "parent connects, orphan resolves" path
+	// does. oldest must not grow past what's actually held.
+	for i := 0; i < 100; i++ {
+		b := orphanBlock(byte(i), 0)
+		om.Add(b)
+		om.Remove(*b.GetHash())
+	}
+
+	if len(om.oldest) != 0 {
+		t.Fatalf("oldest after 100 add/remove cycles = %d entries, want 0", len(om.oldest))
+	}
+	if len(om.orphans) != 0 {
+		t.Fatalf("orphans after 100 add/remove cycles = %d entries, want 0", len(om.orphans))
+	}
+}
+
+func TestOrphanManagerExpire(t *testing.T) {
+	om := NewOrphanManager(10)
+
+	b1 := orphanBlock(1, 0)
+	om.Add(b1)
+	om.orphans[*b1.GetHash()].addedAt = time.Now().Add(-time.Hour)
+
+	b2 := orphanBlock(2, 0)
+	om.Add(b2)
+
+	om.Expire(time.Minute)
+
+	if _, ok := om.orphans[*b1.GetHash()]; ok {
+		t.Fatalf("b1 should have expired")
+	}
+	if _, ok := om.orphans[*b2.GetHash()]; !ok {
+		t.Fatalf("b2 is recent and should not have expired")
+	}
+	if len(om.oldest) != 1 {
+		t.Fatalf("oldest after Expire = %v, want just b2's hash", om.oldest)
+	}
+}
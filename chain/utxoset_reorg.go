@@ -0,0 +1,11 @@
+package chain
+
+// Reverse undoes the effect of every block reorganize has just detached.
+// There is no incremental "undo" bookkeeping for a UTXO set today, so we
+// fall back to a full Rebuild against whatever chain bc.lastBlockHash now
+// points to. reorganize calls this once per reorg, after all of its
+// detached blocks are disconnected, not once per detached block, so the
+// O(chain length) cost is paid a single time regardless of fork depth.
+func (u *UTXOSet) Reverse() {
+	u.Rebuild()
+}
@@ -0,0 +1,51 @@
+package validation
+
+import "errors"
+
+// RuleError wraps a consensus-rule violation so callers can tell "this
+// block/transaction is invalid" apart from I/O errors via errors.As,
+// while still comparing against a specific sentinel with errors.Is.
+type RuleError struct {
+	Err error
+}
+
+func (e *RuleError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+func ruleErr(err error) error {
+	return &RuleError{Err: err}
+}
+
+var (
+	// ErrTimeTooNew is returned when a block's timestamp is further in
+	// the future than the network tolerates.
+	ErrTimeTooNew = errors.New("block timestamp is too far in the future")
+	// ErrOversizeBlock is returned when a block's serialized size exceeds
+	// MaxBlockBaseSize.
+	ErrOversizeBlock = errors.New("serialized block exceeds MaxBlockBaseSize")
+	// ErrBadPoW is returned when a block's hash does not satisfy the
+	// difficulty target implied by its bits.
+	ErrBadPoW = errors.New("block hash does not satisfy its declared difficulty")
+	// ErrDuplicateTx is returned when a block contains the same
+	// transaction id more than once.
+	ErrDuplicateTx = errors.New("block contains a duplicate transaction id")
+	// ErrMissingPrevBlock is returned when a block's previous hash does
+	// not match the block it is being connected onto.
+	ErrMissingPrevBlock = errors.New("block's previous hash does not match its parent")
+	// ErrBadBlockHeight is returned when a block's height does not
+	// immediately follow its parent's.
+	ErrBadBlockHeight = errors.New("block height does not follow its parent")
+	// ErrBadCoinbase is returned when a coinbase transaction pays out
+	// more than the allowed subsidy.
+	ErrBadCoinbase = errors.New("coinbase pays more than the allowed subsidy")
+	// ErrNoTxInputs is returned by a non-coinbase transaction with no
+	// inputs.
+	ErrNoTxInputs = errors.New("transaction has no inputs")
+	// ErrNoTxOutputs is returned by a transaction with no outputs.
+	ErrNoTxOutputs = errors.New("transaction has no outputs")
+)
@@ -0,0 +1,149 @@
+// Package validation holds pure, side-effect-free consensus checks for
+// blocks and transactions. It is deliberately independent of package
+// chain's concrete Block/Transaction types (which would otherwise create
+// an import cycle, since chain calls into validation) and instead takes
+// small summary values the caller extracts once up front.
+package validation
+
+import (
+	"math/big"
+	"time"
+)
+
+// MaxBlockBaseSize is the largest a serialized block is allowed to be.
+const MaxBlockBaseSize = 1000000
+
+// maxTimeOffset mirrors Bitcoin's two-hour allowance for clock skew
+// between the miner and the node validating its block.
+const maxTimeOffset = 2 * 60 * 60
+
+// BlockHeader is the subset of a block's header fields sanity/context
+// checks need.
+type BlockHeader struct {
+	Hash          []byte
+	PrevBlockHash []byte
+	Height        int32
+	Timestamp     int64
+	Bits          int64
+}
+
+// TxSummary is the subset of a transaction's fields sanity checks need.
+type TxSummary struct {
+	ID          []byte
+	IsCoinbase  bool
+	NumInputs   int
+	NumOutputs  int
+	OutputTotal int
+}
+
+// BlockContent is a BlockHeader plus the pieces CheckBlockSanity needs
+// that aren't part of the header itself.
+type BlockContent struct {
+	BlockHeader
+	SerializedSize int
+	Transactions   []TxSummary
+}
+
+// CheckBlockSanity runs the context-free checks on a block: timestamp not
+// too far in the future, maximum size, proof of work, duplicate
+// transactions, and per-transaction sanity. No lower timestamp bound is
+// checked. now is
+// passed in (rather than read via time.Now) so callers can test against a
+// fixed clock.
+func CheckBlockSanity(b BlockContent, powLimit *big.Int, now int64) error {
+	if b.Timestamp > now+maxTimeOffset {
+		return ruleErr(ErrTimeTooNew)
+	}
+
+	if b.SerializedSize > MaxBlockBaseSize {
+		return ruleErr(ErrOversizeBlock)
+	}
+
+	if err := CheckProofOfWork(b.Hash, b.Bits, powLimit); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		id := string(tx.ID)
+		if seen[id] {
+			return ruleErr(ErrDuplicateTx)
+		}
+		seen[id] = true
+
+		if err := CheckTransactionSanity(tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckProofOfWork verifies that hash, read as a big-endian number, does
+// not exceed the target implied by bits, and that bits itself is no
+// looser than powLimit. It is exported separately from CheckBlockSanity
+// so callers that only have a header, not a full block (e.g. headers-first
+// sync validating a batch before requesting the matching blocks), can run
+// the same check.
+func CheckProofOfWork(hash []byte, bits int64, powLimit *big.Int) error {
+	if bits <= 0 || bits > 256 {
+		return ruleErr(ErrBadPoW)
+	}
+
+	target := new(big.Int).Lsh(big.NewInt(1), uint(256-bits))
+	if powLimit != nil && target.Cmp(powLimit) > 0 {
+		return ruleErr(ErrBadPoW)
+	}
+
+	hashNum := new(big.Int).SetBytes(hash)
+	if hashNum.Cmp(target) > 0 {
+		return ruleErr(ErrBadPoW)
+	}
+
+	return nil
+}
+
+// CheckBlockContext checks b against the block it claims to extend: the
+// previous-hash link must match and the height must increase by exactly
+// one.
+func CheckBlockContext(b, prev BlockHeader) error {
+	if string(b.PrevBlockHash) != string(prev.Hash) {
+		return ruleErr(ErrMissingPrevBlock)
+	}
+
+	if b.Height != prev.Height+1 {
+		return ruleErr(ErrBadBlockHeight)
+	}
+
+	return nil
+}
+
+// CheckCoinbaseAmount checks that a block's coinbase output total does not
+// exceed the subsidy it's allowed to claim.
+func CheckCoinbaseAmount(coinbaseOutputTotal, subsidy int) error {
+	if coinbaseOutputTotal > subsidy {
+		return ruleErr(ErrBadCoinbase)
+	}
+	return nil
+}
+
+// CheckTransactionSanity checks that tx has the inputs/outputs a well
+// formed transaction needs: coinbase transactions need no inputs, every
+// other transaction does, and every transaction needs at least one output.
+func CheckTransactionSanity(tx TxSummary) error {
+	if !tx.IsCoinbase && tx.NumInputs == 0 {
+		return ruleErr(ErrNoTxInputs)
+	}
+
+	if tx.NumOutputs == 0 {
+		return ruleErr(ErrNoTxOutputs)
+	}
+
+	return nil
+}
+
+// Now returns the current Unix time, for callers that don't need a fixed
+// clock for testing.
+func Now() int64 {
+	return time.Now().Unix()
+}
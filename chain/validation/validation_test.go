@@ -0,0 +1,112 @@
+package validation
+
+import (
+	"math/big"
+	"testing"
+)
+
+func powLimitForBits(bits int64) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(256-bits))
+}
+
+func TestCheckBlockSanityTimeTooNew(t *testing.T) {
+	b := BlockContent{BlockHeader: BlockHeader{Timestamp: 1000 + maxTimeOffset + 1, Bits: 8}}
+	err := CheckBlockSanity(b, powLimitForBits(8), 1000)
+	if err == nil {
+		t.Fatal("expected ErrTimeTooNew, got nil")
+	}
+	if ruleErr, ok := err.(*RuleError); !ok || ruleErr.Unwrap() != ErrTimeTooNew {
+		t.Fatalf("expected ErrTimeTooNew, got %v", err)
+	}
+}
+
+func TestCheckBlockSanityOversize(t *testing.T) {
+	b := BlockContent{BlockHeader: BlockHeader{Bits: 8}, SerializedSize: MaxBlockBaseSize + 1}
+	err := CheckBlockSanity(b, powLimitForBits(8), 0)
+	if err == nil {
+		t.Fatal("expected ErrOversizeBlock, got nil")
+	}
+	if ruleErr, ok := err.(*RuleError); !ok || ruleErr.Unwrap() != ErrOversizeBlock {
+		t.Fatalf("expected ErrOversizeBlock, got %v", err)
+	}
+}
+
+func TestCheckBlockSanityDuplicateTx(t *testing.T) {
+	tx := TxSummary{ID: []byte("tx1"), IsCoinbase: true, NumOutputs: 1}
+	b := BlockContent{
+		BlockHeader:  BlockHeader{Bits: 8, Hash: make([]byte, 32)},
+		Transactions: []TxSummary{tx, tx},
+	}
+	err := CheckBlockSanity(b, powLimitForBits(8), 0)
+	if err == nil {
+		t.Fatal("expected ErrDuplicateTx, got nil")
+	}
+	if ruleErr, ok := err.(*RuleError); !ok || ruleErr.Unwrap() != ErrDuplicateTx {
+		t.Fatalf("expected ErrDuplicateTx, got %v", err)
+	}
+}
+
+func TestCheckBlockSanityOK(t *testing.T) {
+	b := BlockContent{
+		BlockHeader:    BlockHeader{Bits: 8, Hash: make([]byte, 32)},
+		SerializedSize: 100,
+		Transactions:   []TxSummary{{ID: []byte("tx1"), IsCoinbase: true, NumOutputs: 1}},
+	}
+	if err := CheckBlockSanity(b, powLimitForBits(8), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckBlockContext(t *testing.T) {
+	prev := BlockHeader{Hash: []byte("parent"), Height: 5}
+
+	if err := CheckBlockContext(BlockHeader{PrevBlockHash: []byte("wrong"), Height: 6}, prev); err == nil {
+		t.Fatal("expected ErrMissingPrevBlock, got nil")
+	}
+
+	if err := CheckBlockContext(BlockHeader{PrevBlockHash: []byte("parent"), Height: 7}, prev); err == nil {
+		t.Fatal("expected ErrBadBlockHeight, got nil")
+	}
+
+	if err := CheckBlockContext(BlockHeader{PrevBlockHash: []byte("parent"), Height: 6}, prev); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckCoinbaseAmount(t *testing.T) {
+	if err := CheckCoinbaseAmount(11, 10); err == nil {
+		t.Fatal("expected ErrBadCoinbase, got nil")
+	}
+	if err := CheckCoinbaseAmount(10, 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckTransactionSanity(t *testing.T) {
+	cases := []struct {
+		name    string
+		tx      TxSummary
+		wantErr error
+	}{
+		{"missing inputs", TxSummary{IsCoinbase: false, NumInputs: 0, NumOutputs: 1}, ErrNoTxInputs},
+		{"missing outputs", TxSummary{IsCoinbase: true, NumOutputs: 0}, ErrNoTxOutputs},
+		{"valid coinbase", TxSummary{IsCoinbase: true, NumOutputs: 1}, nil},
+		{"valid spend", TxSummary{IsCoinbase: false, NumInputs: 1, NumOutputs: 1}, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := CheckTransactionSanity(c.tx)
+			if c.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			ruleErr, ok := err.(*RuleError)
+			if !ok || ruleErr.Unwrap() != c.wantErr {
+				t.Fatalf("expected %v, got %v", c.wantErr, err)
+			}
+		})
+	}
+}
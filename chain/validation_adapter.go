@@ -0,0 +1,96 @@
+package chain
+
+import (
+	"math/big"
+
+	"github.com/michain/dotcoin/chain/validation"
+)
+
+// blockSubsidy is the number of coins a coinbase transaction is allowed
+// to pay itself for mining a block.
+const blockSubsidy = 10
+
+// MaxBlockBaseSize re-exports validation.MaxBlockBaseSize, the limit
+// actually enforced by checkBlock below, so callers outside package
+// validation have one place to read the cap from instead of hardcoding
+// their own copy that could drift out of sync.
+const MaxBlockBaseSize = validation.MaxBlockBaseSize
+
+// powLimit is the loosest difficulty target checkBlock (and so
+// ProcessBlock/MineBlock) will accept, derived from blockDefaultDifficult; a block whose bits imply an
+// easier target than this is rejected outright.
+var powLimit = new(big.Int).Lsh(big.NewInt(1), uint(256-blockDefaultDifficult))
+
+// PowLimit returns the loosest difficulty target this node will accept,
+// for callers outside package chain that need to run
+// validation.CheckProofOfWork themselves, e.g. headers-first sync
+// checking a header's PoW before requesting its block.
+func PowLimit() *big.Int {
+	return powLimit
+}
+
+// headerOf extracts the header fields validation needs from block.
+func headerOf(block *Block) validation.BlockHeader {
+	return validation.BlockHeader{
+		Hash:          block.Hash,
+		PrevBlockHash: block.PrevBlockHash,
+		Height:        block.Height,
+		Timestamp:     block.Timestamp,
+		Bits:          block.Bits,
+	}
+}
+
+// txSummaryOf extracts the fields validation needs from tx.
+func txSummaryOf(tx *Transaction) validation.TxSummary {
+	total := 0
+	for _, out := range tx.Outputs {
+		total += out.Value
+	}
+
+	return validation.TxSummary{
+		ID:          tx.ID.CloneBytes(),
+		IsCoinbase:  tx.IsCoinBase(),
+		NumInputs:   len(tx.Inputs),
+		NumOutputs:  len(tx.Outputs),
+		OutputTotal: total,
+	}
+}
+
+// contentOf extracts everything CheckBlockSanity needs from block.
+func contentOf(block *Block) validation.BlockContent {
+	txs := make([]validation.TxSummary, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		txs[i] = txSummaryOf(tx)
+	}
+
+	return validation.BlockContent{
+		BlockHeader:    headerOf(block),
+		SerializedSize: len(SerializeBlock(block)),
+		Transactions:   txs,
+	}
+}
+
+// checkBlock runs every context-aware rule check against block before it
+// is allowed onto the BlockIndex. parent is nil only for the genesis
+// block.
+func checkBlock(block *Block, parent *BlockNode) error {
+	if err := validation.CheckBlockSanity(contentOf(block), powLimit, validation.Now()); err != nil {
+		return err
+	}
+
+	if parent != nil {
+		prevHeader := validation.BlockHeader{Hash: parent.hash.CloneBytes(), Height: parent.height}
+		if err := validation.CheckBlockContext(headerOf(block), prevHeader); err != nil {
+			return err
+		}
+	}
+
+	if len(block.Transactions) > 0 {
+		coinbase := txSummaryOf(block.Transactions[0])
+		if err := validation.CheckCoinbaseAmount(coinbase.OutputTotal, blockSubsidy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -7,7 +7,11 @@ import (
 	"github.com/michain/dotcoin/server"
 )
 
-func (cli *CLI) startNode(nodeID, minerAddress string, isGenesis bool, listenAddr, seedAddr string) {
+// startNode starts the node's server. txIndex turns on the transaction
+// index; chain.LoadBlockChain does a one-time RebuildTxIndex pass over
+// the loaded blockchain when txIndex is set on a datastore that predates
+// it.
+func (cli *CLI) startNode(nodeID, minerAddress string, isGenesis bool, listenAddr, seedAddr string, txIndex bool) {
 	fmt.Printf("Starting node %s\n", nodeID)
 	nodeID = "3eb456d086f34118925793496cd20945"
 	if len(minerAddress) > 0 {
@@ -21,6 +25,6 @@ func (cli *CLI) startNode(nodeID, minerAddress string, isGenesis bool, listenAdd
 		listenAddr = tcpPort
 	}
 
-	server.StartServer(nodeID, minerAddress, listenAddr, seedAddr, isGenesis)
+	server.StartServer(nodeID, minerAddress, listenAddr, seedAddr, isGenesis, txIndex)
 }
 
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/michain/dotcoin/chain"
+	"github.com/michain/dotcoin/chain/validation"
+	"github.com/michain/dotcoin/util/hashx"
+)
+
+const (
+	commandGetHeaders = "getheaders"
+	commandHeaders    = "headers"
+)
+
+// maxHeadersPerMessage bounds a single headers response, the same way
+// maxBlocksInTransit bounds the old per-block request loop.
+const maxHeadersPerMessage = 2000
+
+// getHeaders is the payload of a "getheaders" message: a block locator
+// plus an optional hash to stop at.
+type getHeaders struct {
+	AddrFrom string
+	Locator  [][]byte
+	StopHash []byte
+}
+
+// headersMsg is the payload of a "headers" message: a batch of headers
+// returned in response to getHeaders.
+type headersMsg struct {
+	AddrFrom string
+	Headers  []*chain.BlockHeader
+}
+
+// sendGetHeaders asks addr for headers starting after our own locator,
+// the first step of joining a chain whose fork point we don't know yet.
+func sendGetHeaders(addr string, nodeAddr string, bc *chain.Blockchain) {
+	tip, err := bc.GetLastBlock()
+	var locator []*hashx.Hash
+	if err == nil {
+		locator = bc.BlockLocator(tip.GetHash())
+	}
+
+	payload := gobEncode(getHeaders{
+		AddrFrom: nodeAddr,
+		Locator:  hashesToBytes(locator),
+	})
+	sendCommand(addr, commandGetHeaders, payload)
+}
+
+// handleGetHeaders replies to a getheaders request with up to
+// maxHeadersPerMessage headers found via bc.LocateHeaders.
+func handleGetHeaders(request []byte, nodeAddr string, bc *chain.Blockchain) {
+	var buff bytes.Buffer
+	var payload getHeaders
+
+	buff.Write(request[commandLength:])
+	dec := gob.NewDecoder(&buff)
+	if err := dec.Decode(&payload); err != nil {
+		return
+	}
+
+	var stopHash *hashx.Hash
+	if len(payload.StopHash) > 0 {
+		h := hashx.Hash{}
+		copy(h[:], payload.StopHash)
+		stopHash = &h
+	}
+
+	headers := bc.LocateHeaders(bytesToHashes(payload.Locator), stopHash, maxHeadersPerMessage)
+
+	data := gobEncode(headersMsg{AddrFrom: nodeAddr, Headers: headers})
+	sendCommand(payload.AddrFrom, commandHeaders, data)
+}
+
+// handleHeaders validates the chain linkage and PoW of a batch of headers
+// and, once satisfied they're genuine, requests the matching full blocks.
+func handleHeaders(request []byte, bc *chain.Blockchain) {
+	var buff bytes.Buffer
+	var payload headersMsg
+
+	buff.Write(request[commandLength:])
+	dec := gob.NewDecoder(&buff)
+	if err := dec.Decode(&payload); err != nil {
+		return
+	}
+
+	for i, header := range payload.Headers {
+		if i > 0 && string(header.PrevBlockHash) != string(payload.Headers[i-1].Hash) {
+			// headers don't chain together; drop the whole batch
+			return
+		}
+		if err := validation.CheckProofOfWork(header.Hash, header.Bits, chain.PowLimit()); err != nil {
+			// not enough real work behind this header; drop the whole
+			// batch rather than paying for any of its blocks
+			return
+		}
+	}
+
+	for _, header := range payload.Headers {
+		have, err := bc.HaveBlock(hashFromHeaderHash(header.Hash))
+		if err == nil && !have {
+			sendGetData(payload.AddrFrom, "block", header.Hash)
+		}
+	}
+}
+
+// HandleMessage dispatches an incoming command to its headers-first sync
+// handler, if command is one this file handles, and reports whether it
+// did. The node's main command dispatch switch is expected to call this
+// (alongside its existing "block"/"inv"/"tx"/... cases) for "getheaders"
+// and "headers"; that dispatch switch lives in the server package's
+// connection-handling code, which this tree doesn't include, so it isn't
+// wired in yet.
+func HandleMessage(command string, request []byte, nodeAddr string, bc *chain.Blockchain) bool {
+	switch command {
+	case commandGetHeaders:
+		handleGetHeaders(request, nodeAddr, bc)
+	case commandHeaders:
+		handleHeaders(request, bc)
+	default:
+		return false
+	}
+	return true
+}
+
+// StartHeadersSync kicks off headers-first sync against addr: send our
+// locator and let the peer's "headers" reply (handled above) pull down
+// whatever full blocks we're missing. This is meant to replace whatever
+// currently calls the old GetBlockHashes single-hash cursor as the sync
+// entry point; that call site isn't part of this tree either, so
+// StartHeadersSync isn't called from anywhere yet.
+func StartHeadersSync(addr, nodeAddr string, bc *chain.Blockchain) {
+	sendGetHeaders(addr, nodeAddr, bc)
+}
+
+func hashFromHeaderHash(b []byte) *hashx.Hash {
+	h := hashx.Hash{}
+	copy(h[:], b)
+	return &h
+}
+
+func hashesToBytes(hashes []*hashx.Hash) [][]byte {
+	out := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		out[i] = h.CloneBytes()
+	}
+	return out
+}
+
+func bytesToHashes(raw [][]byte) []*hashx.Hash {
+	out := make([]*hashx.Hash, len(raw))
+	for i, b := range raw {
+		out[i] = hashFromHeaderHash(b)
+	}
+	return out
+}
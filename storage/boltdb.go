@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BoltBlocksBucket holds every block, keyed by its hash, plus the
+	// BoltLastHashKey entry pointing at the current chain tip.
+	BoltBlocksBucket = "blocks"
+	// BoltLastHashKey is the key inside BoltBlocksBucket that holds the
+	// chain tip's hash.
+	BoltLastHashKey = "l"
+	// BoltHeightIndexBucket holds the main-chain hash for every height,
+	// so a restart can tell a main-chain block from one on a side branch.
+	BoltHeightIndexBucket = "height_index"
+	// BoltUTXOBucket holds the unspent outputs for every transaction,
+	// keyed by transaction ID.
+	BoltUTXOBucket = "chainstate"
+	// BoltTxIndexBucket holds, for every indexed transaction, the hash of
+	// the block it was confirmed in plus its offset among that block's
+	// transactions.
+	BoltTxIndexBucket = "tx_index"
+)
+
+// ErrorBlockNotFount is returned when a block hash has no stored data.
+var ErrorBlockNotFount = errors.New("block is not found")
+
+// GetDBFileName returns the boltdb file path for nodeID.
+func GetDBFileName(nodeID string) string {
+	return fmt.Sprintf("blockchain_%s.db", nodeID)
+}
+
+// boltStore is the production Store backed by a boltdb file.
+type boltStore struct {
+	db *bolt.DB
+}
+
+func init() {
+	Register("boltdb", newBoltStore)
+}
+
+func newBoltStore(nodeID string) (Store, error) {
+	db, err := bolt.Open(GetDBFileName(nodeID), 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range []string{BoltBlocksBucket, BoltHeightIndexBucket, BoltUTXOBucket, BoltTxIndexBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) SaveBlock(hash, data []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltBlocksBucket)).Put(hash, data)
+	})
+}
+
+func (s *boltStore) GetBlock(hash []byte) ([]byte, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(BoltBlocksBucket)).Get(hash)
+		if v == nil {
+			return ErrorBlockNotFount
+		}
+		data = append(data, v...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *boltStore) GetLastBlock() ([]byte, []byte, error) {
+	var hash, data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BoltBlocksBucket))
+		hash = append(hash, b.Get([]byte(BoltLastHashKey))...)
+		if len(hash) > 0 {
+			data = append(data, b.Get(hash)...)
+		}
+		return nil
+	})
+	return hash, data, err
+}
+
+func (s *boltStore) PutLastHash(hash []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltBlocksBucket)).Put([]byte(BoltLastHashKey), hash)
+	})
+}
+
+func (s *boltStore) IterateBlocks(fn func(hash, data []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltBlocksBucket)).ForEach(func(k, v []byte) error {
+			if string(k) == BoltLastHashKey {
+				return nil
+			}
+			return fn(k, v)
+		})
+	})
+}
+
+func heightKey(height int32) []byte {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, uint32(height))
+	return key
+}
+
+func (s *boltStore) PutMainChainHash(height int32, hash []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltHeightIndexBucket)).Put(heightKey(height), hash)
+	})
+}
+
+func (s *boltStore) DeleteMainChainHash(height int32) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltHeightIndexBucket)).Delete(heightKey(height))
+	})
+}
+
+func (s *boltStore) GetMainChainHash(height int32) ([]byte, error) {
+	var hash []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte(BoltHeightIndexBucket)).Get(heightKey(height)); v != nil {
+			hash = append(hash, v...)
+		}
+		return nil
+	})
+	return hash, err
+}
+
+func (s *boltStore) IterateHeightIndex(fn func(height int32, hash []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltHeightIndexBucket)).ForEach(func(k, v []byte) error {
+			return fn(int32(binary.BigEndian.Uint32(k)), v)
+		})
+	})
+}
+
+func (s *boltStore) GetUTXO(txID []byte) ([]byte, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte(BoltUTXOBucket)).Get(txID); v != nil {
+			data = append(data, v...)
+		}
+		return nil
+	})
+	return data, err
+}
+
+func (s *boltStore) PutUTXO(txID []byte, data []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltUTXOBucket)).Put(txID, data)
+	})
+}
+
+func (s *boltStore) DeleteUTXO(txID []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltUTXOBucket)).Delete(txID)
+	})
+}
+
+func (s *boltStore) IterateUTXO(fn func(txID, data []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltUTXOBucket)).ForEach(fn)
+	})
+}
+
+func (s *boltStore) ClearUTXO() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(BoltUTXOBucket)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket([]byte(BoltUTXOBucket))
+		return err
+	})
+}
+
+// txIndexValue packs a tx index entry as blockHash followed by a
+// big-endian offset, so it fits in a single bolt value.
+func txIndexValue(blockHash []byte, offset int32) []byte {
+	v := make([]byte, len(blockHash)+4)
+	copy(v, blockHash)
+	binary.BigEndian.PutUint32(v[len(blockHash):], uint32(offset))
+	return v
+}
+
+func parseTxIndexValue(v []byte) (blockHash []byte, offset int32) {
+	blockHash = append([]byte{}, v[:len(v)-4]...)
+	offset = int32(binary.BigEndian.Uint32(v[len(v)-4:]))
+	return
+}
+
+func (s *boltStore) PutTxIndex(txID, blockHash []byte, offset int32) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltTxIndexBucket)).Put(txID, txIndexValue(blockHash, offset))
+	})
+}
+
+func (s *boltStore) GetTxIndex(txID []byte) ([]byte, int32, error) {
+	var blockHash []byte
+	var offset int32
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte(BoltTxIndexBucket)).Get(txID); v != nil {
+			blockHash, offset = parseTxIndexValue(v)
+		}
+		return nil
+	})
+	return blockHash, offset, err
+}
+
+func (s *boltStore) DeleteTxIndex(txID []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltTxIndexBucket)).Delete(txID)
+	})
+}
+
+func (s *boltStore) IterateTxIndex(fn func(txID, blockHash []byte, offset int32) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltTxIndexBucket)).ForEach(func(k, v []byte) error {
+			blockHash, offset := parseTxIndexValue(v)
+			return fn(k, blockHash, offset)
+		})
+	})
+}
+
+func (s *boltStore) ClearTxIndex() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(BoltTxIndexBucket)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket([]byte(BoltTxIndexBucket))
+		return err
+	})
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
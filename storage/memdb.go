@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+)
+
+// memStore is an in-memory Store, so unit tests can exercise
+// CreateBlockchain/MineBlock/FindUTXO without touching disk.
+type memStore struct {
+	lock sync.RWMutex
+
+	blocks      map[string][]byte
+	lastHash    []byte
+	heightIndex map[int32][]byte
+	utxo        map[string][]byte
+	txIndex     map[string]txIndexEntry
+}
+
+// txIndexEntry is the memdb-native form of a tx index entry.
+type txIndexEntry struct {
+	blockHash []byte
+	offset    int32
+}
+
+func init() {
+	Register("memdb", newMemStore)
+}
+
+// newMemStore creates a fresh, empty memStore. nodeID is accepted to
+// satisfy the Driver signature but otherwise ignored: every
+// Open("memdb", ...) call gets its own isolated store.
+func newMemStore(nodeID string) (Store, error) {
+	return &memStore{
+		blocks:      make(map[string][]byte),
+		heightIndex: make(map[int32][]byte),
+		utxo:        make(map[string][]byte),
+		txIndex:     make(map[string]txIndexEntry),
+	}, nil
+}
+
+func (s *memStore) SaveBlock(hash, data []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cp := make([]byte, len(data))
+	copy(cp, data)
+	s.blocks[string(hash)] = cp
+	return nil
+}
+
+func (s *memStore) GetBlock(hash []byte) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	data, ok := s.blocks[string(hash)]
+	if !ok {
+		return nil, ErrorBlockNotFount
+	}
+	return data, nil
+}
+
+func (s *memStore) GetLastBlock() ([]byte, []byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.lastHash) == 0 {
+		return nil, nil, nil
+	}
+	return s.lastHash, s.blocks[string(s.lastHash)], nil
+}
+
+func (s *memStore) PutLastHash(hash []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cp := make([]byte, len(hash))
+	copy(cp, hash)
+	s.lastHash = cp
+	return nil
+}
+
+func (s *memStore) IterateBlocks(fn func(hash, data []byte) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for hash, data := range s.blocks {
+		if err := fn([]byte(hash), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memStore) PutMainChainHash(height int32, hash []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cp := make([]byte, len(hash))
+	copy(cp, hash)
+	s.heightIndex[height] = cp
+	return nil
+}
+
+func (s *memStore) DeleteMainChainHash(height int32) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.heightIndex, height)
+	return nil
+}
+
+func (s *memStore) GetMainChainHash(height int32) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.heightIndex[height], nil
+}
+
+func (s *memStore) IterateHeightIndex(fn func(height int32, hash []byte) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	heights := make([]int32, 0, len(s.heightIndex))
+	for height := range s.heightIndex {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	for _, height := range heights {
+		if err := fn(height, s.heightIndex[height]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memStore) GetUTXO(txID []byte) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.utxo[string(txID)], nil
+}
+
+func (s *memStore) PutUTXO(txID []byte, data []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cp := make([]byte, len(data))
+	copy(cp, data)
+	s.utxo[string(txID)] = cp
+	return nil
+}
+
+func (s *memStore) DeleteUTXO(txID []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.utxo, string(txID))
+	return nil
+}
+
+func (s *memStore) IterateUTXO(fn func(txID, data []byte) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for txID, data := range s.utxo {
+		if err := fn([]byte(txID), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memStore) ClearUTXO() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.utxo = make(map[string][]byte)
+	return nil
+}
+
+func (s *memStore) PutTxIndex(txID, blockHash []byte, offset int32) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cp := make([]byte, len(blockHash))
+	copy(cp, blockHash)
+	s.txIndex[string(txID)] = txIndexEntry{blockHash: cp, offset: offset}
+	return nil
+}
+
+func (s *memStore) GetTxIndex(txID []byte) ([]byte, int32, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	entry, ok := s.txIndex[string(txID)]
+	if !ok {
+		return nil, 0, nil
+	}
+	return entry.blockHash, entry.offset, nil
+}
+
+func (s *memStore) DeleteTxIndex(txID []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.txIndex, string(txID))
+	return nil
+}
+
+func (s *memStore) IterateTxIndex(fn func(txID, blockHash []byte, offset int32) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for txID, entry := range s.txIndex {
+		if err := fn([]byte(txID), entry.blockHash, entry.offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memStore) ClearTxIndex() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.txIndex = make(map[string]txIndexEntry)
+	return nil
+}
+
+func (s *memStore) Close() error {
+	return nil
+}
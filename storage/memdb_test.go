@@ -0,0 +1,93 @@
+package storage
+
+import "testing"
+
+func TestMemStoreBlockRoundTrip(t *testing.T) {
+	s, err := newMemStore("node")
+	if err != nil {
+		t.Fatalf("newMemStore: %v", err)
+	}
+
+	hash := []byte("hash1")
+	data := []byte("block data")
+	if err := s.SaveBlock(hash, data); err != nil {
+		t.Fatalf("SaveBlock: %v", err)
+	}
+
+	got, err := s.GetBlock(hash)
+	if err != nil {
+		t.Fatalf("GetBlock: %v", err)
+	}
+	if string(got) != string(data) {
+		t.Fatalf("GetBlock = %q, want %q", got, data)
+	}
+
+	if _, err := s.GetBlock([]byte("missing")); err != ErrorBlockNotFount {
+		t.Fatalf("GetBlock(missing) = %v, want ErrorBlockNotFount", err)
+	}
+}
+
+func TestMemStoreIterateHeightIndexAscending(t *testing.T) {
+	s, err := newMemStore("node")
+	if err != nil {
+		t.Fatalf("newMemStore: %v", err)
+	}
+
+	heights := []int32{5, 1, 3, 2, 4}
+	for _, h := range heights {
+		if err := s.PutMainChainHash(h, []byte{byte(h)}); err != nil {
+			t.Fatalf("PutMainChainHash(%d): %v", h, err)
+		}
+	}
+
+	var seen []int32
+	err = s.IterateHeightIndex(func(height int32, hash []byte) error {
+		seen = append(seen, height)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("IterateHeightIndex: %v", err)
+	}
+
+	want := []int32{1, 2, 3, 4, 5}
+	if len(seen) != len(want) {
+		t.Fatalf("IterateHeightIndex visited %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("IterateHeightIndex visited %v, want ascending %v", seen, want)
+		}
+	}
+}
+
+func TestMemStoreTxIndexRoundTrip(t *testing.T) {
+	s, err := newMemStore("node")
+	if err != nil {
+		t.Fatalf("newMemStore: %v", err)
+	}
+
+	txID := []byte("tx1")
+	blockHash := []byte("block1")
+	if err := s.PutTxIndex(txID, blockHash, 2); err != nil {
+		t.Fatalf("PutTxIndex: %v", err)
+	}
+
+	gotHash, gotOffset, err := s.GetTxIndex(txID)
+	if err != nil {
+		t.Fatalf("GetTxIndex: %v", err)
+	}
+	if string(gotHash) != string(blockHash) || gotOffset != 2 {
+		t.Fatalf("GetTxIndex = (%q, %d), want (%q, 2)", gotHash, gotOffset, blockHash)
+	}
+
+	if err := s.DeleteTxIndex(txID); err != nil {
+		t.Fatalf("DeleteTxIndex: %v", err)
+	}
+	if gotHash, _, err := s.GetTxIndex(txID); err != nil || len(gotHash) != 0 {
+		t.Fatalf("GetTxIndex after delete = (%q, %v), want empty", gotHash, err)
+	}
+
+	if err := s.ClearTxIndex(); err != nil {
+		t.Fatalf("ClearTxIndex: %v", err)
+	}
+}
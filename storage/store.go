@@ -0,0 +1,87 @@
+package storage
+
+import "fmt"
+
+// Store is the persistence interface Blockchain, UTXOSet and
+// BlockchainIterator depend on. It lets the chain package work against
+// boltdb in production and an in-memory driver in tests, without either
+// side knowing about the other.
+type Store interface {
+	// SaveBlock stores the serialized block data under hash.
+	SaveBlock(hash, data []byte) error
+	// GetBlock returns the serialized block data for hash, or
+	// ErrorBlockNotFount if it isn't stored.
+	GetBlock(hash []byte) ([]byte, error)
+	// GetLastBlock returns the chain tip's hash and serialized data.
+	// Both are nil if no block has been saved yet.
+	GetLastBlock() (hash, data []byte, err error)
+	// PutLastHash records hash as the chain tip.
+	PutLastHash(hash []byte) error
+	// IterateBlocks calls fn once per stored block, in no particular order.
+	IterateBlocks(fn func(hash, data []byte) error) error
+
+	// PutMainChainHash records hash as the main-chain block at height.
+	PutMainChainHash(height int32, hash []byte) error
+	// DeleteMainChainHash removes the main-chain record at height.
+	DeleteMainChainHash(height int32) error
+	// GetMainChainHash returns the main-chain hash recorded at height,
+	// or nil if none is recorded.
+	GetMainChainHash(height int32) ([]byte, error)
+	// IterateHeightIndex calls fn once per recorded (height, hash) pair,
+	// in ascending height order.
+	IterateHeightIndex(fn func(height int32, hash []byte) error) error
+
+	// GetUTXO returns the serialized unspent outputs for txID, or nil if
+	// txID has none recorded.
+	GetUTXO(txID []byte) ([]byte, error)
+	// PutUTXO stores the serialized unspent outputs for txID.
+	PutUTXO(txID []byte, data []byte) error
+	// DeleteUTXO removes any unspent outputs recorded for txID.
+	DeleteUTXO(txID []byte) error
+	// IterateUTXO calls fn once per (txID, data) pair in the UTXO bucket.
+	IterateUTXO(fn func(txID, data []byte) error) error
+	// ClearUTXO empties the UTXO bucket, used before a full Rebuild.
+	ClearUTXO() error
+
+	// PutTxIndex records that txID was confirmed in the block hashed
+	// blockHash, at position offset among that block's transactions.
+	PutTxIndex(txID, blockHash []byte, offset int32) error
+	// GetTxIndex returns the block hash and offset recorded for txID, or
+	// a nil blockHash if txID isn't indexed.
+	GetTxIndex(txID []byte) (blockHash []byte, offset int32, err error)
+	// DeleteTxIndex removes any index entry recorded for txID.
+	DeleteTxIndex(txID []byte) error
+	// IterateTxIndex calls fn once per (txID, blockHash, offset) entry in
+	// the tx index, in no particular order.
+	IterateTxIndex(fn func(txID, blockHash []byte, offset int32) error) error
+	// ClearTxIndex empties the tx index, used before a full rebuild.
+	ClearTxIndex() error
+
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+// Driver opens a Store for nodeID. Drivers register themselves by name
+// via Register, typically from an init func in the same file.
+type Driver func(nodeID string) (Store, error)
+
+var drivers = make(map[string]Driver)
+
+// Register makes a Store driver available under name. It panics if name
+// is already registered, matching the convention used by e.g. database/sql.
+func Register(name string, driver Driver) {
+	if _, exists := drivers[name]; exists {
+		panic(fmt.Sprintf("storage: driver %q already registered", name))
+	}
+	drivers[name] = driver
+}
+
+// Open opens a Store using the named driver. Supported names are
+// "boltdb" and "memdb".
+func Open(driverName, nodeID string) (Store, error) {
+	driver, ok := drivers[driverName]
+	if !ok {
+		return nil, fmt.Errorf("storage: unknown driver %q", driverName)
+	}
+	return driver(nodeID)
+}